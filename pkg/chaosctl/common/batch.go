@@ -0,0 +1,168 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gertd/go-pluralize"
+)
+
+// batchListArguments answers one ListArguments-shaped question per field in
+// fields, but issues a single query round-trip instead of len(fields)
+// separate ones. Each field gets its own top-level alias (f0, f1, ...), the
+// same way a hand-written batched GraphQL document would:
+//
+//	query { f0: pods(...) { name } f1: ioChaos(...) { name } }
+//
+// completeQuery previously called ListArguments once per field with
+// arguments, which is the O(fields x depth) round-trip cost this replaces;
+// CompleteQuery's public signature is unaffected.
+func (c *CtrlClient) batchListArguments(ctxQuery []string, fields []*Field) ([][]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	queryType, err := c.GetQueryType()
+	if err != nil {
+		return nil, err
+	}
+
+	helper := pluralize.NewClient()
+
+	type part struct {
+		alias     string
+		query     *Query
+		field     reflect.StructField
+		variables map[string]interface{}
+		startWith string
+	}
+
+	parts := make([]part, len(fields))
+
+	for i, field := range fields {
+		fieldName := string(field.Name)
+		argumentName := string(field.Args[0].Name)
+
+		queryStr := append(append([]string{}, ctxQuery...), fieldName)
+		listQuery := append([]string{}, queryStr[:len(queryStr)-1]...)
+		listQuery[len(listQuery)-1] = helper.Plural(listQuery[len(listQuery)-1])
+		listQuery = append(listQuery, argumentName)
+
+		query, err := c.Schema.ParseQuery(listQuery, queryType)
+		if err != nil {
+			return nil, err
+		}
+
+		alias := fmt.Sprintf("f%d", i)
+		aliasQuery := NewQuery("query", queryType, nil)
+		aliasQuery.Fields[alias] = query
+		variables := NewVariables()
+
+		// listQuery never incorporates fieldName (it's built from ctxQuery,
+		// which every field in this batch shares), so two fields with the
+		// same first argument name (e.g. "name") produce an identical
+		// listQuery; cache on fieldName too or they'd collide onto each
+		// other's cached aliasStruct, corrupting the field tag folded into
+		// structFields below.
+		cacheKey := append(append([]string{}, listQuery...), fieldName)
+		aliasStruct, variablesMap, err := c.SchemaCache.ReflectType(cacheKey, func() (reflect.Type, map[string]interface{}, error) {
+			t, err := c.reflectQuery(alias, query, aliasQuery, variables)
+			if err != nil {
+				return nil, nil, err
+			}
+			return t, variables.GenMap(), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		namespaced := namespaceVariables(variablesMap, alias)
+		structField := renameGraphQLVariables(aliasStruct.Elem().Field(0), namespaced.rename)
+
+		parts[i] = part{
+			alias:     alias,
+			query:     query,
+			field:     structField,
+			variables: namespaced.values,
+			startWith: fieldName,
+		}
+	}
+
+	structFields := make([]reflect.StructField, len(parts))
+	combinedVariables := make(map[string]interface{})
+
+	for i, p := range parts {
+		structFields[i] = p.field
+		for name, value := range p.variables {
+			combinedVariables[name] = value
+		}
+	}
+
+	combinedValue := reflect.New(reflect.StructOf(structFields)).Interface()
+	if err := c.Client.Query(c.ctx, combinedValue, combinedVariables); err != nil {
+		return nil, err
+	}
+
+	value := reflect.ValueOf(combinedValue).Elem()
+
+	results := make([][]string, len(fields))
+	for i, p := range parts {
+		arguments, err := listArguments(value.Field(i).Interface(), p.query, p.startWith)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = arguments
+	}
+
+	return results, nil
+}
+
+type namespacedVariables struct {
+	rename map[string]string // original variable name -> namespaced name
+	values map[string]interface{}
+}
+
+// namespaceVariables renames every variable in vars with an alias prefix, so
+// sibling aliased fields sharing an argument name (e.g. "namespace") don't
+// collide once folded into one query document.
+func namespaceVariables(vars map[string]interface{}, alias string) namespacedVariables {
+	out := namespacedVariables{
+		rename: make(map[string]string, len(vars)),
+		values: make(map[string]interface{}, len(vars)),
+	}
+
+	for name, value := range vars {
+		namespaced := alias + "_" + name
+		out.rename[name] = namespaced
+		out.values[namespaced] = value
+	}
+
+	return out
+}
+
+// renameGraphQLVariables rewrites the "$name" variable references embedded
+// in field's graphql struct tag to match the renaming produced by
+// namespaceVariables.
+func renameGraphQLVariables(field reflect.StructField, rename map[string]string) reflect.StructField {
+	tag := field.Tag.Get("graphql")
+	for from, to := range rename {
+		tag = strings.ReplaceAll(tag, "$"+from, "$"+to)
+	}
+
+	field.Tag = reflect.StructTag(fmt.Sprintf(`graphql:"%s"`, tag))
+	return field
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespaceVariablesPrefixesEveryName(t *testing.T) {
+	out := namespaceVariables(map[string]interface{}{"name": "foo", "namespace": "default"}, "f0")
+
+	if out.rename["name"] != "f0_name" || out.rename["namespace"] != "f0_namespace" {
+		t.Fatalf("unexpected rename map: %#v", out.rename)
+	}
+	if out.values["f0_name"] != "foo" || out.values["f0_namespace"] != "default" {
+		t.Fatalf("unexpected values map: %#v", out.values)
+	}
+	if len(out.values) != 2 {
+		t.Fatalf("expected values to only contain namespaced keys, got %#v", out.values)
+	}
+}
+
+func TestRenameGraphQLVariablesDisambiguatesSiblingAliases(t *testing.T) {
+	// f0 and f1 both filter on $name - exactly the collision the review
+	// comment called out for the ReflectType cache key; this exercises the
+	// companion GraphQL-tag rewrite that makes the two aliased fields safe
+	// to fold into one query document once their variables are namespaced.
+	f0 := reflect.StructField{
+		Name: "Pods",
+		Tag:  `graphql:"pods(name: $name)"`,
+	}
+	f1 := reflect.StructField{
+		Name: "IoChaos",
+		Tag:  `graphql:"ioChaos(name: $name)"`,
+	}
+
+	r0 := renameGraphQLVariables(f0, namespaceVariables(map[string]interface{}{"name": "a"}, "f0").rename)
+	r1 := renameGraphQLVariables(f1, namespaceVariables(map[string]interface{}{"name": "b"}, "f1").rename)
+
+	if got := r0.Tag.Get("graphql"); got != "pods(name: $f0_name)" {
+		t.Fatalf("f0 tag = %q, want pods(name: $f0_name)", got)
+	}
+	if got := r1.Tag.Get("graphql"); got != "ioChaos(name: $f1_name)" {
+		t.Fatalf("f1 tag = %q, want ioChaos(name: $f1_name)", got)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/iancoleman/strcase"
+)
+
+// bindings holds the generated Go types registered by pkg/chaosctl/gen
+// output, keyed by GraphQL type name. CtrlClient prefers a registered
+// binding over Schema.Reflect's runtime reflection whenever one is present.
+var bindings = struct {
+	mu sync.RWMutex
+	m  map[string]reflect.Type
+}{m: make(map[string]reflect.Type)}
+
+// RegisterBinding registers a generated Go type for typeName. Generated
+// files call this from an init function, so importing generated bindings
+// for side effect is enough to make CtrlClient prefer them.
+func RegisterBinding(typeName string, goType reflect.Type) {
+	bindings.mu.Lock()
+	defer bindings.mu.Unlock()
+	bindings.m[typeName] = goType
+}
+
+func lookupBinding(typeName string) (reflect.Type, bool) {
+	bindings.mu.RLock()
+	defer bindings.mu.RUnlock()
+	t, ok := bindings.m[typeName]
+	return t, ok
+}
+
+// Resolve exposes Schema.resolve to pkg/chaosctl/gen, which lives in a
+// separate package and so cannot call the unexported method directly.
+func (s *Schema) Resolve(ref *TypeRef) (*Type, error) {
+	return s.resolve(ref)
+}
+
+// reflectQuery prefers a generated binding for the field wrapper selects,
+// and falls back to Schema.Reflect's runtime reflection otherwise.
+//
+// Every call site builds wrapper as a synthetic top-level operation (via
+// NewQuery("query"/"mutation"/"subscription", ...)) with a single selection
+// named wrapperField; wrapper.Name is therefore always that literal
+// operation keyword, never a schema type name RegisterBinding could have
+// registered. The real target type lives on target, the Query ParseQuery
+// resolved for wrapperField, so the binding lookup has to happen there
+// instead. A hit still needs to be shaped back into the single-field struct
+// wrapper.Name expects Schema.Reflect to have produced, since the generated
+// binding only covers the selected field's own type, not the synthetic
+// wrapper around it - and that shape must keep target's own call signature
+// (its GraphQL field name, alias if wrapperField differs from it, and bound
+// arguments), or the emitted document silently drops required arguments and
+// falls back to selecting the binding's default shape.
+func (c *CtrlClient) reflectQuery(wrapperField string, target, wrapper *Query, variables *Variables) (reflect.Type, error) {
+	if target != nil && target.Type != nil {
+		if bound, ok := lookupBinding(string(target.Type.Name)); ok {
+			field := reflect.StructField{
+				Name: strcase.ToCamel(wrapperField),
+				Type: reflect.PtrTo(bound),
+				Tag:  reflect.StructTag(fmt.Sprintf(`graphql:"%s"`, fieldCallTag(wrapperField, target))),
+			}
+			return reflect.PtrTo(reflect.StructOf([]reflect.StructField{field})), nil
+		}
+	}
+
+	return c.Schema.Reflect(wrapper, variables)
+}
+
+// fieldCallTag renders target's own GraphQL call signature - its field name,
+// an "alias: " prefix if wrapperField selects it under a different name (as
+// batchListArguments does), and "(arg: $arg, ...)" for each of its bound
+// arguments - the same shape Schema.Reflect would have produced for a single
+// selection. Argument variable names match the argument's own name, which is
+// what BindArguments binds under and what batch.go's renameGraphQLVariables
+// later namespaces textually when folding aliased siblings into one query.
+func fieldCallTag(wrapperField string, target *Query) string {
+	fieldName := string(target.Name)
+
+	call := fieldName
+	if len(target.Args) > 0 {
+		args := make([]string, len(target.Args))
+		for i, arg := range target.Args {
+			args[i] = fmt.Sprintf("%s: $%s", arg.Name, arg.Name)
+		}
+		call = fmt.Sprintf("%s(%s)", fieldName, strings.Join(args, ", "))
+	}
+
+	if wrapperField != fieldName {
+		return fmt.Sprintf("%s: %s", wrapperField, call)
+	}
+	return call
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldCallTagPreservesArguments(t *testing.T) {
+	target := &Query{
+		Name: "pods",
+		Type: &Type{Name: "Pod"},
+		Args: []*Argument{{Name: "namespace"}, {Name: "name"}},
+	}
+
+	if got, want := fieldCallTag("pods", target), "pods(namespace: $namespace, name: $name)"; got != want {
+		t.Fatalf("fieldCallTag = %q, want %q", got, want)
+	}
+}
+
+func TestFieldCallTagAliasesWhenWrapperFieldDiffers(t *testing.T) {
+	// batchListArguments selects target under an alias ("f0") distinct from
+	// its own field name; the call signature must keep the alias prefix, the
+	// way GraphQL field aliasing requires.
+	target := &Query{
+		Name: "pods",
+		Type: &Type{Name: "Pod"},
+		Args: []*Argument{{Name: "name"}},
+	}
+
+	if got, want := fieldCallTag("f0", target), "f0: pods(name: $name)"; got != want {
+		t.Fatalf("fieldCallTag = %q, want %q", got, want)
+	}
+}
+
+func TestFieldCallTagNoArguments(t *testing.T) {
+	target := &Query{Name: "namespace", Type: &Type{Name: "Namespace"}}
+
+	if got, want := fieldCallTag("namespace", target), "namespace"; got != want {
+		t.Fatalf("fieldCallTag = %q, want %q", got, want)
+	}
+}
+
+func TestReflectQueryBindingFastPathPreservesArguments(t *testing.T) {
+	bound := reflect.TypeOf(struct {
+		Name string `graphql:"name"`
+	}{})
+	RegisterBinding("Pod", bound)
+	defer func() {
+		bindings.mu.Lock()
+		delete(bindings.m, "Pod")
+		bindings.mu.Unlock()
+	}()
+
+	target := &Query{
+		Name: "pods",
+		Type: &Type{Name: "Pod"},
+		Args: []*Argument{{Name: "namespace"}},
+	}
+
+	c := &CtrlClient{}
+	typ, err := c.reflectQuery("pods", target, nil, nil)
+	if err != nil {
+		t.Fatalf("reflectQuery returned error: %v", err)
+	}
+
+	field := typ.Elem().Field(0)
+	if got, want := field.Tag.Get("graphql"), "pods(namespace: $namespace)"; got != want {
+		t.Fatalf("binding fast path tag = %q, want %q (arguments must survive)", got, want)
+	}
+	if field.Type != reflect.PtrTo(bound) {
+		t.Fatalf("binding fast path field type = %v, want *%v", field.Type, bound)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SchemaCache persists the introspected schema on disk and memoizes the
+// reflect.Type query shapes Schema.Reflect produces for a given query path,
+// so a short-lived chaosctl invocation doesn't re-fetch and re-reflect on
+// every run.
+type SchemaCache struct {
+	dir string
+
+	mu           sync.Mutex
+	reflectTypes map[string]reflectedQuery
+}
+
+// reflectedQuery is what ReflectType memoizes: the decode shape Reflect
+// produced, together with the GraphQL variables it bound while walking the
+// query. Both are reproducible from queryPath alone, since the literal
+// argument values a query binds (e.g. a chosen namespace or resource name)
+// are themselves embedded in queryPath, so two calls sharing a path also
+// share the resulting variables.
+type reflectedQuery struct {
+	typ       reflect.Type
+	variables map[string]interface{}
+}
+
+type cachedSchema struct {
+	BuildHash string    `json:"buildHash"`
+	Schema    RawSchema `json:"schema"`
+}
+
+// NewSchemaCache creates a SchemaCache rooted at
+// $XDG_CACHE_HOME/chaosctl, falling back to the platform's default user
+// cache directory when XDG_CACHE_HOME is unset. The on-disk half of the
+// cache is a pure performance optimization, not a dependency: if no cache
+// directory can be resolved or created (no $HOME, a read-only filesystem,
+// ...), the returned SchemaCache just runs with dir left empty, and
+// Load/Store quietly become no-ops rather than failing every caller.
+func NewSchemaCache() *SchemaCache {
+	dir, _ := schemaCacheDir()
+	return &SchemaCache{dir: dir, reflectTypes: make(map[string]reflectedQuery)}
+}
+
+func schemaCacheDir() (string, bool) {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return "", false
+		}
+		base = userCache
+	}
+
+	dir := filepath.Join(base, "chaosctl")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", false
+	}
+
+	return dir, true
+}
+
+func (sc *SchemaCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(sc.dir, fmt.Sprintf("schema-%s.json", hex.EncodeToString(sum[:])))
+}
+
+// Load returns the schema cached for url, provided its recorded buildHash
+// still matches the control plane's current one; a stale cache from a
+// since-upgraded controller is never served.
+func (sc *SchemaCache) Load(url, buildHash string) (*RawSchema, bool) {
+	if sc.dir == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(sc.path(url))
+	if err != nil {
+		return nil, false
+	}
+
+	var cached cachedSchema
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+
+	if cached.BuildHash != buildHash {
+		return nil, false
+	}
+
+	return &cached.Schema, true
+}
+
+// Store writes schema to disk for url, keyed by buildHash so that a future
+// Load is automatically invalidated once the control plane's build changes.
+// A no-op when the disk cache is unavailable (see NewSchemaCache).
+func (sc *SchemaCache) Store(url, buildHash string, schema *RawSchema) error {
+	if sc.dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cachedSchema{BuildHash: buildHash, Schema: *schema})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(sc.path(url), data, 0o644)
+}
+
+// ReflectType memoizes the reflect.Type Schema.Reflect produces for a query
+// path, along with the GraphQL variables it bound while doing so, keyed by
+// the path's segments in order. Repeated ListArguments calls for the same
+// path (common while CompleteQuery walks its recursion) skip Schema.Reflect
+// entirely; compute is only invoked on a miss, so it is also where variable
+// binding must happen - see the call sites in ctrlclient.go and batch.go.
+func (sc *SchemaCache) ReflectType(queryPath []string, compute func() (reflect.Type, map[string]interface{}, error)) (reflect.Type, map[string]interface{}, error) {
+	key := reflectCacheKey(queryPath)
+
+	sc.mu.Lock()
+	if cached, ok := sc.reflectTypes[key]; ok {
+		sc.mu.Unlock()
+		return cached.typ, cached.variables, nil
+	}
+	sc.mu.Unlock()
+
+	typ, variables, err := compute()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sc.mu.Lock()
+	sc.reflectTypes[key] = reflectedQuery{typ: typ, variables: variables}
+	sc.mu.Unlock()
+
+	return typ, variables, nil
+}
+
+// reflectCacheKey joins queryPath's segments in order. Order must be
+// preserved (not sorted) - two structurally distinct paths can share the
+// same set of segment names, and sorting would collide them onto the same
+// cached shape.
+func reflectCacheKey(queryPath []string) string {
+	return strings.Join(queryPath, "/")
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReflectCacheKeyPreservesOrder(t *testing.T) {
+	a := reflectCacheKey([]string{"namespace", "default", "pods"})
+	b := reflectCacheKey([]string{"pods", "default", "namespace"})
+
+	if a == b {
+		t.Fatalf("reflectCacheKey must not collide distinct orderings, both produced %q", a)
+	}
+	if got, want := reflectCacheKey([]string{"a", "b"}), "a/b"; got != want {
+		t.Fatalf("reflectCacheKey = %q, want %q", got, want)
+	}
+}
+
+func TestSchemaCacheReflectTypeMemoizesComputeResult(t *testing.T) {
+	sc := &SchemaCache{reflectTypes: make(map[string]reflectedQuery)}
+
+	calls := 0
+	compute := func() (reflect.Type, map[string]interface{}, error) {
+		calls++
+		return reflect.TypeOf(struct{ Name string }{}), map[string]interface{}{"name": "foo"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		typ, vars, err := sc.ReflectType([]string{"pods", "name"}, compute)
+		if err != nil {
+			t.Fatalf("ReflectType returned error: %v", err)
+		}
+		if typ != reflect.TypeOf(struct{ Name string }{}) {
+			t.Fatalf("unexpected type on call %d: %v", i, typ)
+		}
+		if vars["name"] != "foo" {
+			t.Fatalf("unexpected variables on call %d: %#v", i, vars)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("compute was called %d times, want 1 (cache should have hit on repeat calls)", calls)
+	}
+}
+
+func TestSchemaCacheReflectTypeDistinctKeysDoNotCollide(t *testing.T) {
+	sc := &SchemaCache{reflectTypes: make(map[string]reflectedQuery)}
+
+	type podsShape struct{ Pods string }
+	type ioChaosShape struct{ IoChaos string }
+
+	_, _, err := sc.ReflectType([]string{"namespace", "default", "pods", "name", "pods"}, func() (reflect.Type, map[string]interface{}, error) {
+		return reflect.TypeOf(podsShape{}), nil, nil
+	})
+	if err != nil {
+		t.Fatalf("ReflectType returned error: %v", err)
+	}
+
+	got, _, err := sc.ReflectType([]string{"namespace", "default", "pods", "name", "ioChaos"}, func() (reflect.Type, map[string]interface{}, error) {
+		return reflect.TypeOf(ioChaosShape{}), nil, nil
+	})
+	if err != nil {
+		t.Fatalf("ReflectType returned error: %v", err)
+	}
+
+	if got != reflect.TypeOf(ioChaosShape{}) {
+		t.Fatalf("second field got back the first field's cached type: %v", got)
+	}
+}
+
+func TestSchemaCacheDiskOperationsAreNoOpsWhenDirUnavailable(t *testing.T) {
+	sc := &SchemaCache{reflectTypes: make(map[string]reflectedQuery)}
+
+	if err := sc.Store("https://example.com/graphql", "hash", &RawSchema{}); err != nil {
+		t.Fatalf("Store with no cache dir should be a no-op, got error: %v", err)
+	}
+
+	if _, ok := sc.Load("https://example.com/graphql", "hash"); ok {
+		t.Fatal("Load with no cache dir should always miss")
+	}
+}
@@ -16,9 +16,11 @@ package common
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gertd/go-pluralize"
 	prmt "github.com/gitchander/permutation"
@@ -36,6 +38,27 @@ type CtrlClient struct {
 	Client             *graphql.Client
 	SubscriptionClient *graphql.SubscriptionClient
 	Schema             *Schema
+	SchemaCache        *SchemaCache
+
+	subsMu sync.Mutex
+	subs   map[string]*subscription
+}
+
+// CtrlClientOption configures optional behavior of NewCtrlClient, such as
+// enabling persisted queries.
+type CtrlClientOption func(*ctrlClientConfig)
+
+type ctrlClientConfig struct {
+	persisted bool
+}
+
+// WithPersistedQueries enables Apollo-style automatic persisted queries:
+// CtrlClient sends only a query's SHA-256 hash, falling back to the full
+// document when the control plane reports PersistedQueryNotFound.
+func WithPersistedQueries() CtrlClientOption {
+	return func(cfg *ctrlClientConfig) {
+		cfg.persisted = true
+	}
 }
 
 type AutoCompleteContext struct {
@@ -90,23 +113,62 @@ func (ctx *AutoCompleteContext) Next(typename, fieldName, arg string) *AutoCompl
 	}
 }
 
-func NewCtrlClient(ctx context.Context, url string) (*CtrlClient, error) {
+func NewCtrlClient(ctx context.Context, url string, opts ...CtrlClientOption) (*CtrlClient, error) {
+	cfg := &ctrlClientConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	httpClient := http.DefaultClient
+	if cfg.persisted {
+		httpClient = &http.Client{Transport: &persistedQueryTransport{next: http.DefaultTransport}}
+	}
+
+	// NewSchemaCache never fails: a missing or read-only cache directory
+	// just degrades the disk cache to in-memory-only, it isn't a reason to
+	// refuse to connect.
+	cache := NewSchemaCache()
+
 	client := &CtrlClient{
 		ctx:                ctx,
-		Client:             graphql.NewClient(url, nil),
+		Client:             graphql.NewClient(url, httpClient),
 		SubscriptionClient: graphql.NewSubscriptionClient(url),
+		SchemaCache:        cache,
+		subs:               make(map[string]*subscription),
 	}
 
-	schemaQuery := new(struct {
-		Schema RawSchema `graphql:"__schema"`
+	// buildHash is used only to key the on-disk schema cache; a control
+	// plane that doesn't expose it (older servers, or one built without
+	// that field) simply gets no cache hit, not a failed connection.
+	var buildHash string
+	buildHashQuery := new(struct {
+		BuildHash graphql.String `graphql:"buildHash"`
 	})
+	if err := client.Client.Query(client.ctx, buildHashQuery, nil); err == nil {
+		buildHash = string(buildHashQuery.BuildHash)
+	}
 
-	err := client.Client.Query(client.ctx, schemaQuery, nil)
-	if err != nil {
-		return nil, err
+	if cached, ok := cache.Load(url, buildHash); buildHash != "" && ok {
+		client.Schema = NewSchema(cached)
+	} else {
+		schemaQuery := new(struct {
+			Schema RawSchema `graphql:"__schema"`
+		})
+
+		if err := client.Client.Query(client.ctx, schemaQuery, nil); err != nil {
+			return nil, err
+		}
+
+		// Storing is a pure cache-warming side effect: a failure here (e.g. a
+		// read-only cache directory) must not fail a connection that already
+		// has a working schema in hand.
+		_ = cache.Store(url, buildHash, &schemaQuery.Schema)
+
+		client.Schema = NewSchema(&schemaQuery.Schema)
 	}
 
-	client.Schema = NewSchema(&schemaQuery.Schema)
+	client.SubscriptionClient.OnDisconnected(client.resubscribeAll)
+
 	return client, nil
 }
 
@@ -134,13 +196,22 @@ func (c *CtrlClient) ListArguments(queryStr []string, argumentName string) ([]st
 	superQuery.Fields["namespace"] = query
 	variables := NewVariables()
 
-	queryStruct, err := c.Schema.Reflect(superQuery, variables)
+	// cache by listQuery, not queryStr: queryStr's last element is the
+	// partially-typed value a user is completing, not part of the query's
+	// shape, so keying on it would defeat the cache for every keystroke.
+	queryStruct, variablesMap, err := c.SchemaCache.ReflectType(listQuery, func() (reflect.Type, map[string]interface{}, error) {
+		t, err := c.reflectQuery("namespace", query, superQuery, variables)
+		if err != nil {
+			return nil, nil, err
+		}
+		return t, variables.GenMap(), nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	queryValue := reflect.New(queryStruct.Elem()).Interface()
-	err = c.Client.Query(c.ctx, queryValue, variables.GenMap())
+	err = c.Client.Query(c.ctx, queryValue, variablesMap)
 	if err != nil {
 		return nil, err
 	}
@@ -218,7 +289,7 @@ func (c *CtrlClient) CompleteQuery(namespace string, completeLeaves bool) ([]str
 	return completion, nil
 }
 
-// accepts ScalarKind, EnumKind and ObjectKind
+// accepts ScalarKind, EnumKind, ObjectKind and InputObjectKind
 func (c *CtrlClient) completeQuery(ctx *AutoCompleteContext, root *Type) ([]string, error) {
 	if ctx.IsComplete() {
 		return nil, nil
@@ -227,11 +298,16 @@ func (c *CtrlClient) completeQuery(ctx *AutoCompleteContext, root *Type) ([]stri
 	switch root.Kind {
 	case ScalarKind, EnumKind:
 		return nil, nil
+	case InputObjectKind:
+		return c.completeInputObject(root)
 	case ListKind, NonNullKind:
 		return nil, fmt.Errorf("type is not supported to complete: %#v", root)
 	}
 
 	var trunks, leaves []string
+	var argFields []*Field
+	var argSubTypes []*Type
+
 	for _, field := range root.Fields {
 		subType, err := c.Schema.resolve(&field.Type)
 		if err != nil {
@@ -261,12 +337,21 @@ func (c *CtrlClient) completeQuery(ctx *AutoCompleteContext, root *Type) ([]stri
 			continue
 		}
 
-		args, err := c.ListArguments(append(ctx.query, string(field.Name)), string(field.Args[0].Name))
-		if err != nil {
-			return nil, err
-		}
+		argFields = append(argFields, field)
+		argSubTypes = append(argSubTypes, subType)
+	}
 
-		for _, arg := range args {
+	// batch every pending argument-list query for this recursion pass into a
+	// single round-trip instead of issuing one per field.
+	argResults, err := c.batchListArguments(ctx.query, argFields)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, field := range argFields {
+		subType := argSubTypes[i]
+
+		for _, arg := range argResults[i] {
 			subQueries, err := c.completeQuery(ctx.Next(string(subType.Name), string(field.Name), arg), subType)
 			if err != nil {
 				return nil, err
@@ -275,7 +360,6 @@ func (c *CtrlClient) completeQuery(ctx *AutoCompleteContext, root *Type) ([]stri
 			for _, subQuery := range subQueries {
 				trunks = append(trunks, strings.Join([]string{string(field.Name), arg, subQuery}, "/"))
 			}
-			continue
 		}
 	}
 
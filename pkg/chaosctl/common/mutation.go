@@ -0,0 +1,125 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const MutationType = "Mutation"
+
+// GetMutationType returns the root type used to resolve mutations, mirroring
+// (*CtrlClient).GetQueryType for the query root.
+func (c *CtrlClient) GetMutationType() (*Type, error) {
+	return c.Schema.MustGetMutationType()
+}
+
+// MustGetMutationType resolves mutationType from the introspected schema,
+// the same way MustGetType resolves an arbitrary named type.
+func (s *Schema) MustGetMutationType() (*Type, error) {
+	if s.MutationType == nil {
+		return nil, fmt.Errorf("schema does not expose a mutation type")
+	}
+
+	return s.MustGetType(string(s.MutationType.Name))
+}
+
+// Mutate reflects path against the mutation root the same way ListArguments
+// reflects a query path against queryType, binds input to the field's
+// arguments through the existing Variables machinery, and executes the
+// mutation. path addresses a single mutation field, e.g.
+// []string{"podChaos", "apply"}; input supplies its arguments by name.
+func (c *CtrlClient) Mutate(path []string, input map[string]any) (interface{}, error) {
+	mutationType, err := c.GetMutationType()
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := c.Schema.ParseQuery(path, mutationType)
+	if err != nil {
+		return nil, err
+	}
+
+	superQuery := NewQuery("mutation", mutationType, nil)
+	superQuery.Fields[path[0]] = query
+	variables := NewVariables()
+
+	if err := variables.BindArguments(query, input); err != nil {
+		return nil, err
+	}
+
+	mutationStruct, err := c.reflectQuery(path[0], query, superQuery, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	mutationValue := reflect.New(mutationStruct.Elem()).Interface()
+	if err := c.Client.Mutate(c.ctx, mutationValue, variables.GenMap()); err != nil {
+		return nil, err
+	}
+
+	return mutationValue, nil
+}
+
+// CompleteMutationQuery completes a field path rooted at the mutation type,
+// the same way CompleteQuery completes one rooted at queryType.
+func (c *CtrlClient) CompleteMutationQuery(namespace string, completeLeaves bool) ([]string, error) {
+	mutationType, err := c.GetMutationType()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.completeQuery(NewAutoCompleteContext(namespace, 6, completeLeaves), mutationType)
+}
+
+// completeInputObject suggests required (non-null) argument field names for
+// an InputObject type, expanding enum-kind fields into their allowed values
+// (e.g. "duration" or "action/pod-kill") so callers composing a mutation by
+// hand get the same field discovery queries already get.
+func (c *CtrlClient) completeInputObject(root *Type) ([]string, error) {
+	var suggestions []string
+
+	for _, field := range root.InputFields {
+		if field.Type.Kind != NonNullKind {
+			continue
+		}
+
+		fieldType, err := c.Schema.resolve(&field.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, inputFieldSuggestions(field, fieldType)...)
+	}
+
+	return suggestions, nil
+}
+
+// inputFieldSuggestions expands a single resolved input field into its
+// completion suggestions: the bare field name for any non-enum kind, or one
+// "field/value" suggestion per allowed enum value.
+func inputFieldSuggestions(field *Field, fieldType *Type) []string {
+	if fieldType.Kind != EnumKind {
+		return []string{string(field.Name)}
+	}
+
+	suggestions := make([]string, 0, len(fieldType.EnumValues))
+	for _, value := range fieldType.EnumValues {
+		suggestions = append(suggestions, strings.Join([]string{string(field.Name), string(value.Name)}, "/"))
+	}
+
+	return suggestions
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInputFieldSuggestionsScalarField(t *testing.T) {
+	field := &Field{Name: "duration"}
+	fieldType := &Type{Kind: ScalarKind, Name: "String"}
+
+	got := inputFieldSuggestions(field, fieldType)
+	want := []string{"duration"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("inputFieldSuggestions = %v, want %v", got, want)
+	}
+}
+
+func TestInputFieldSuggestionsExpandsEnumValues(t *testing.T) {
+	field := &Field{Name: "action"}
+	fieldType := &Type{
+		Kind: EnumKind,
+		Name: "PodChaosAction",
+		EnumValues: []*EnumValue{
+			{Name: "pod-kill"},
+			{Name: "pod-failure"},
+		},
+	}
+
+	got := inputFieldSuggestions(field, fieldType)
+	want := []string{"action/pod-kill", "action/pod-failure"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("inputFieldSuggestions = %v, want %v", got, want)
+	}
+}
+
+func TestInputFieldSuggestionsEnumWithNoValues(t *testing.T) {
+	field := &Field{Name: "action"}
+	fieldType := &Type{Kind: EnumKind, Name: "Empty"}
+
+	if got := inputFieldSuggestions(field, fieldType); len(got) != 0 {
+		t.Fatalf("inputFieldSuggestions = %v, want empty", got)
+	}
+}
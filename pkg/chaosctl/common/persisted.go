@@ -0,0 +1,135 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// persistedQueryTransport implements Apollo-style automatic persisted
+// queries on top of graphql.Client's normal HTTP transport: every request
+// first sends only the SHA-256 hash of its query document, and only resends
+// the full text once the server reports PersistedQueryNotFound.
+type persistedQueryTransport struct {
+	next http.RoundTripper
+}
+
+type graphQLRequestBody struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (t *persistedQueryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Method != http.MethodPost {
+		return t.next.RoundTrip(req)
+	}
+
+	raw, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+
+	var body graphQLRequestBody
+	if err := json.Unmarshal(raw, &body); err != nil {
+		// not a GraphQL request body we recognize, pass through untouched.
+		req.Body = io.NopCloser(bytes.NewReader(raw))
+		return t.next.RoundTrip(req)
+	}
+
+	hash := sha256.Sum256([]byte(body.Query))
+	hashHex := hex.EncodeToString(hash[:])
+	extensions := map[string]interface{}{
+		"persistedQuery": map[string]interface{}{
+			"version":    1,
+			"sha256Hash": hashHex,
+		},
+	}
+
+	hashed := body
+	hashed.Query = ""
+	hashed.Extensions = extensions
+
+	resp, err := t.send(req, hashed)
+	if err != nil {
+		return nil, err
+	}
+
+	notFound, err := isPersistedQueryNotFound(resp)
+	if err != nil {
+		return nil, err
+	}
+	if !notFound {
+		return resp, nil
+	}
+
+	full := body
+	full.Extensions = extensions
+	return t.send(req, full)
+}
+
+func (t *persistedQueryTransport) send(req *http.Request, body graphQLRequestBody) (*http.Response, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(encoded))
+	clone.ContentLength = int64(len(encoded))
+
+	return t.next.RoundTrip(clone)
+}
+
+// isPersistedQueryNotFound reports whether resp carries the
+// "PersistedQueryNotFound" error GraphQL servers return for an unrecognized
+// query hash, restoring resp.Body so it can still be read normally
+// afterwards.
+func isPersistedQueryNotFound(resp *http.Response) (bool, error) {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(raw))
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+
+	var parsed struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return false, nil
+	}
+
+	for _, e := range parsed.Errors {
+		if strings.Contains(e.Message, "PersistedQueryNotFound") {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+const SubscriptionType = "Subscription"
+
+// subscriptionBacklog bounds how many payloads Subscribe will buffer for a
+// handler that is falling behind. Once full, newer payloads are dropped
+// rather than blocking the websocket read loop.
+const subscriptionBacklog = 16
+
+// subscription tracks enough state to resubscribe a live query after the
+// underlying websocket reconnects.
+type subscription struct {
+	queryStr []string
+	handler  func(payload json.RawMessage) error
+	payloads chan json.RawMessage
+	done     chan struct{}
+
+	// currentID is the server-assigned subscription ID sub is registered
+	// under in c.subs right now. resubscribeAll rewrites it (under
+	// c.subsMu) every time it re-issues sub against a new connection, so
+	// onHandlerError - set up once by Subscribe - always unsubscribes the
+	// ID that's actually live instead of a stale one from before the last
+	// reconnect.
+	currentID string
+
+	// onHandlerError is invoked once, in its own goroutine, the first time
+	// handler returns an error - set by Subscribe once the server-assigned
+	// subscription ID is known, so run() doesn't need a reference back to
+	// CtrlClient itself. This is what makes handler returning an error
+	// actually unsubscribe the query, rather than just stop local delivery.
+	onHandlerError func()
+}
+
+// GetSubscriptionType returns the root type used to resolve subscription
+// queries, mirroring (*CtrlClient).GetQueryType for the query root.
+func (c *CtrlClient) GetSubscriptionType() (*Type, error) {
+	if c.Schema.SubscriptionType == nil {
+		return nil, fmt.Errorf("schema does not expose a subscription type")
+	}
+
+	return c.Schema.MustGetType(string(c.Schema.SubscriptionType.Name))
+}
+
+// CompleteSubscriptionQuery completes a query path rooted at the subscription
+// type, the same way CompleteQuery completes one rooted at queryType.
+func (c *CtrlClient) CompleteSubscriptionQuery(namespace string, completeLeaves bool) ([]string, error) {
+	subscriptionType, err := c.GetSubscriptionType()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.completeQuery(NewAutoCompleteContext(namespace, 6, completeLeaves), subscriptionType)
+}
+
+// Subscribe reflects queryStr against the subscription root the same way
+// ListArguments reflects queryStr against queryType, then opens a
+// subscription over the existing graphql-transport-ws connection. handler is
+// invoked with the raw payload of every event; an error returned from
+// handler unsubscribes the query.
+//
+// Payloads are delivered to handler through a bounded channel so a slow
+// handler cannot stall the websocket read loop; once the channel is full,
+// further payloads are dropped until handler catches up. If the connection
+// drops and reconnects, Subscribe is re-issued automatically against the
+// new connection.
+func (c *CtrlClient) Subscribe(queryStr []string, handler func(payload json.RawMessage) error) (string, error) {
+	sub := &subscription{
+		queryStr: queryStr,
+		handler:  handler,
+		payloads: make(chan json.RawMessage, subscriptionBacklog),
+		done:     make(chan struct{}),
+	}
+
+	subID, err := c.startSubscription(sub)
+	if err != nil {
+		return "", err
+	}
+
+	// Reads sub.currentID under subsMu at the time handler actually errors,
+	// rather than capturing subID now, so a handler error after one or more
+	// reconnects still unsubscribes the ID that's currently live.
+	sub.onHandlerError = func() {
+		c.subsMu.Lock()
+		id := sub.currentID
+		c.subsMu.Unlock()
+		_ = c.Unsubscribe(id)
+	}
+
+	c.trackSubscription(subID, sub)
+
+	go sub.run()
+
+	return subID, nil
+}
+
+// trackSubscription registers sub under id in c.subs and refreshes
+// sub.currentID to match, both under subsMu; an entry still registering sub
+// under its previous ID is dropped. Used by Subscribe for the
+// initial ID and by resubscribeAll for every ID a reconnect reassigns, so
+// onHandlerError - which reads sub.currentID at error time - always sees
+// whichever ID is actually live on the server.
+func (c *CtrlClient) trackSubscription(id string, sub *subscription) {
+	c.subsMu.Lock()
+	if old := sub.currentID; old != "" && old != id && c.subs[old] == sub {
+		delete(c.subs, old)
+	}
+	sub.currentID = id
+	c.subs[id] = sub
+	c.subsMu.Unlock()
+}
+
+// Unsubscribe stops delivering payloads for subID and tears down the
+// subscription on the server.
+func (c *CtrlClient) Unsubscribe(subID string) error {
+	c.subsMu.Lock()
+	sub, ok := c.subs[subID]
+	delete(c.subs, subID)
+	c.subsMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown subscription: %s", subID)
+	}
+
+	// Tell the server to stop the subscription before closing payloads:
+	// startSubscription's delivery callback still runs concurrently on the
+	// websocket read-loop goroutine until the client is actually told to
+	// unsubscribe, and it sends on sub.payloads - closing that channel
+	// first would race a send against an already-closed channel and panic.
+	err := c.SubscriptionClient.Unsubscribe(subID)
+	close(sub.payloads)
+	<-sub.done
+
+	return err
+}
+
+// resubscribeAll is registered as the SubscriptionClient's OnDisconnected
+// hook, so every tracked subscription is re-issued against the new
+// connection after a transient websocket failure.
+func (c *CtrlClient) resubscribeAll() {
+	c.subsMu.Lock()
+	subs := make(map[string]*subscription, len(c.subs))
+	for id, sub := range c.subs {
+		subs[id] = sub
+	}
+	c.subs = make(map[string]*subscription)
+	c.subsMu.Unlock()
+
+	for _, sub := range subs {
+		newID, err := c.startSubscription(sub)
+		if err != nil {
+			// the server is still unreachable; drop this subscription rather
+			// than leak it, the caller will observe no further payloads.
+			continue
+		}
+
+		c.trackSubscription(newID, sub)
+	}
+}
+
+func (c *CtrlClient) startSubscription(sub *subscription) (string, error) {
+	subscriptionType, err := c.GetSubscriptionType()
+	if err != nil {
+		return "", err
+	}
+
+	query, err := c.Schema.ParseQuery(sub.queryStr, subscriptionType)
+	if err != nil {
+		return "", err
+	}
+
+	superQuery := NewQuery("subscription", subscriptionType, nil)
+	superQuery.Fields[sub.queryStr[0]] = query
+	variables := NewVariables()
+
+	queryStruct, err := c.reflectQuery(sub.queryStr[0], query, superQuery, variables)
+	if err != nil {
+		return "", err
+	}
+
+	queryValue := reflect.New(queryStruct.Elem()).Interface()
+
+	return c.SubscriptionClient.Subscribe(queryValue, variables.GenMap(), func(data []byte, subErr error) error {
+		if subErr != nil {
+			return subErr
+		}
+
+		select {
+		case sub.payloads <- json.RawMessage(data):
+		default:
+			// handler is falling behind; drop this payload instead of
+			// blocking the websocket read loop.
+		}
+
+		return nil
+	})
+}
+
+func (sub *subscription) run() {
+	defer close(sub.done)
+
+	for payload := range sub.payloads {
+		if err := sub.handler(payload); err != nil {
+			// onHandlerError calls back into Unsubscribe, which waits on
+			// sub.done - run it in its own goroutine so that wait isn't
+			// blocked on this call returning first.
+			if sub.onHandlerError != nil {
+				go sub.onHandlerError()
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionRunDeliversPayloadsInOrder(t *testing.T) {
+	sub := &subscription{
+		payloads: make(chan json.RawMessage, subscriptionBacklog),
+		done:     make(chan struct{}),
+	}
+
+	var mu sync.Mutex
+	var got []string
+	sub.handler = func(payload json.RawMessage) error {
+		mu.Lock()
+		got = append(got, string(payload))
+		mu.Unlock()
+		return nil
+	}
+
+	go sub.run()
+
+	sub.payloads <- json.RawMessage("one")
+	sub.payloads <- json.RawMessage("two")
+	close(sub.payloads)
+
+	select {
+	case <-sub.done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not close done after payloads channel closed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Fatalf("got %v, want [one two]", got)
+	}
+}
+
+func TestSubscriptionRunUnsubscribesOnHandlerError(t *testing.T) {
+	sub := &subscription{
+		payloads: make(chan json.RawMessage, subscriptionBacklog),
+		done:     make(chan struct{}),
+	}
+	sub.handler = func(payload json.RawMessage) error {
+		return errors.New("handler failed")
+	}
+
+	called := make(chan struct{})
+	sub.onHandlerError = func() {
+		close(called)
+	}
+
+	go sub.run()
+	sub.payloads <- json.RawMessage("boom")
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("onHandlerError was never invoked after handler returned an error")
+	}
+
+	select {
+	case <-sub.done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not stop after handler returned an error")
+	}
+}
+
+func TestOnHandlerErrorUsesCurrentIDAfterResubscribe(t *testing.T) {
+	c := &CtrlClient{subs: make(map[string]*subscription)}
+	sub := &subscription{
+		payloads: make(chan json.RawMessage, subscriptionBacklog),
+		done:     make(chan struct{}),
+	}
+
+	// Mirrors what Subscribe does: wire onHandlerError once, reading
+	// sub.currentID at error time rather than capturing the ID it was
+	// given up front.
+	called := make(chan struct{})
+	sub.onHandlerError = func() {
+		defer close(called)
+		c.subsMu.Lock()
+		id := sub.currentID
+		c.subsMu.Unlock()
+		if id != "new-id" {
+			t.Errorf("onHandlerError resolved to %q, want new-id (stale ID would leak the subscription after reconnect)", id)
+		}
+	}
+
+	c.trackSubscription("old-id", sub)
+	// Simulates what resubscribeAll does after a reconnect re-issues sub
+	// under a new server-assigned ID, without depending on a live
+	// Schema/SubscriptionClient to drive startSubscription itself.
+	c.trackSubscription("new-id", sub)
+
+	if _, ok := c.subs["old-id"]; ok {
+		t.Fatal("trackSubscription should not leave the subscription registered under its previous ID")
+	}
+	if got := c.subs["new-id"]; got != sub {
+		t.Fatal("trackSubscription did not register sub under the new ID")
+	}
+
+	sub.handler = func(payload json.RawMessage) error {
+		return errors.New("handler failed")
+	}
+	go sub.run()
+	sub.payloads <- json.RawMessage("boom")
+
+	select {
+	case <-sub.done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not stop after handler returned an error")
+	}
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("onHandlerError was never invoked after handler returned an error")
+	}
+}
+
+func TestSubscriptionRunStopsWithoutOnHandlerError(t *testing.T) {
+	// onHandlerError is nil until Subscribe assigns it (it needs the
+	// server-assigned subscription ID, which only exists after
+	// startSubscription returns); run must tolerate that window rather than
+	// panicking on a nil call.
+	sub := &subscription{
+		payloads: make(chan json.RawMessage, subscriptionBacklog),
+		done:     make(chan struct{}),
+	}
+	sub.handler = func(payload json.RawMessage) error {
+		return errors.New("handler failed")
+	}
+
+	go sub.run()
+	sub.payloads <- json.RawMessage("boom")
+
+	select {
+	case <-sub.done:
+	case <-time.After(time.Second):
+		t.Fatal("run did not stop after handler returned an error")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chaos-mesh/chaos-mesh/pkg/chaosctl/common"
+)
+
+type genOptions struct {
+	outDir  string
+	pkgName string
+}
+
+// NewCommand returns the `chaosctl gen` subcommand, which generates typed Go
+// bindings for the control plane's current schema under --out.
+func NewCommand() *cobra.Command {
+	o := &genOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate typed Go bindings from the controller's GraphQL schema",
+		Long: "Fetches the introspection schema the same way chaosctl's other commands do " +
+			"and emits strongly-typed Go structs under --out, so CtrlClient can bind to " +
+			"them directly instead of reflecting over graphql.Client.Query results.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url, err := cmd.Flags().GetString("ctrlManagerEndpoint")
+			if err != nil {
+				return err
+			}
+
+			client, err := common.NewCtrlClient(context.Background(), url)
+			if err != nil {
+				return err
+			}
+
+			return NewGenerator(client.Schema, o.outDir, o.pkgName).Generate()
+		},
+	}
+
+	cmd.Flags().StringVar(&o.outDir, "out", "pkg/chaosctl/gen/bindings", "output directory for generated bindings")
+	cmd.Flags().StringVar(&o.pkgName, "package", "bindings", "package name for generated bindings")
+
+	return cmd
+}
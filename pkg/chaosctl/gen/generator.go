@@ -0,0 +1,363 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gen binds the introspected GraphQL schema that CtrlClient already
+// fetches in NewCtrlClient to strongly-typed Go structs, the same way
+// gqlgen binds a schema file to Go types at generate time. Generating
+// bindings lets CtrlClient skip the reflect.ValueOf/strcase walk in
+// pkg/chaosctl/common for any type a binding exists for, see
+// (*common.CtrlClient) reflectQuery.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/chaos-mesh/chaos-mesh/pkg/chaosctl/common"
+)
+
+// Generator emits one Go file per object/input/interface/union/enum type
+// reachable from the schema's query, mutation and subscription roots. The
+// query/mutation/subscription root types additionally get a <Field>Path()
+// helper per root field, so callers don't have to spell root field names
+// out by hand when building the path passed to Mutate/Subscribe/ListArguments.
+type Generator struct {
+	schema *common.Schema
+	outDir string
+	// pkgName is the package name of generated files, as passed to
+	// NewGenerator (the gen subcommand's --package flag).
+	pkgName string
+}
+
+// NewGenerator returns a Generator that writes bindings for schema into
+// outDir.
+func NewGenerator(schema *common.Schema, outDir, pkgName string) *Generator {
+	return &Generator{schema: schema, outDir: outDir, pkgName: pkgName}
+}
+
+// Generate walks every root field of the query, mutation and subscription
+// types (skipping roots the schema does not expose) and writes the Go
+// bindings for the types they resolve to.
+func (g *Generator) Generate() error {
+	if err := os.MkdirAll(g.outDir, 0o755); err != nil {
+		return fmt.Errorf("creating output dir %s: %w", g.outDir, err)
+	}
+
+	visited := make(map[string]bool)
+
+	roots := []string{}
+	if g.schema.QueryType != nil {
+		roots = append(roots, string(g.schema.QueryType.Name))
+	}
+	if g.schema.MutationType != nil {
+		roots = append(roots, string(g.schema.MutationType.Name))
+	}
+	if g.schema.SubscriptionType != nil {
+		roots = append(roots, string(g.schema.SubscriptionType.Name))
+	}
+
+	for _, rootName := range roots {
+		root, err := g.schema.MustGetType(rootName)
+		if err != nil {
+			return err
+		}
+
+		if err := g.generateRoot(root, visited); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateRoot writes root's binding file the same way generateType would,
+// plus one path helper per root field (see renderStruct's isRoot handling),
+// then recurses into the rest of the reachable subgraph exactly like
+// generateType.
+func (g *Generator) generateRoot(root *common.Type, visited map[string]bool) error {
+	name := string(root.Name)
+	if visited[name] {
+		return nil
+	}
+	visited[name] = true
+
+	if err := g.writeFile(name, g.renderStruct(root, true)); err != nil {
+		return err
+	}
+
+	return g.generateFields(root, visited)
+}
+
+// generateType writes typeDef's binding file, recursing into every field's
+// resolved type so the whole reachable subgraph gets bindings.
+func (g *Generator) generateType(typeDef *common.Type, visited map[string]bool) error {
+	name := string(typeDef.Name)
+	if visited[name] {
+		return nil
+	}
+	visited[name] = true
+
+	switch typeDef.Kind {
+	case common.ScalarKind:
+		return nil
+	case common.EnumKind:
+		return g.writeFile(name, g.renderEnum(typeDef))
+	case common.InterfaceKind, common.UnionKind:
+		if err := g.writeFile(name, g.renderUnion(typeDef)); err != nil {
+			return err
+		}
+	default:
+		if err := g.writeFile(name, g.renderStruct(typeDef, false)); err != nil {
+			return err
+		}
+	}
+
+	return g.generateFields(typeDef, visited)
+}
+
+// generateFields recurses generateType into every type typeDef's own fields
+// (or input fields, for an InputObject) and possible types (for an
+// interface/union) resolve to, the shared tail of generateRoot and
+// generateType.
+func (g *Generator) generateFields(typeDef *common.Type, visited map[string]bool) error {
+	fields := typeDef.Fields
+	if typeDef.Kind == common.InputObjectKind {
+		fields = typeDef.InputFields
+	}
+
+	for _, field := range fields {
+		subType, err := g.schema.Resolve(&field.Type)
+		if err != nil {
+			return err
+		}
+
+		if err := g.generateType(subType, visited); err != nil {
+			return err
+		}
+	}
+
+	for _, possible := range typeDef.PossibleTypes {
+		subType, err := g.schema.MustGetType(string(possible.Name))
+		if err != nil {
+			return err
+		}
+
+		if err := g.generateType(subType, visited); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *Generator) writeFile(typeName, body string) error {
+	formatted, err := format.Source([]byte(body))
+	if err != nil {
+		// keep the unformatted source on disk so the generator failure is
+		// easy to diagnose, rather than silently dropping the file.
+		formatted = []byte(body)
+	}
+
+	path := filepath.Join(g.outDir, strcase.ToSnake(typeName)+".gen.go")
+	return os.WriteFile(path, formatted, 0o644)
+}
+
+// goFieldType maps a GraphQL field type to the Go type used in generated
+// structs, handling NonNull/List wrapping the same way Schema.Reflect does
+// for the reflection-based path.
+//
+// Object/Interface/Union/InputObject fields are pointer-wrapped: t.Kind
+// carries the real underlying kind at every level (not just NON_NULL/LIST),
+// so this is the one place that can tell a nested object field apart from a
+// scalar/enum one. Without the pointer, a self- or mutually-referential
+// schema (e.g. anything shaped like introspection's own __Type.ofType)
+// produces a Go struct that embeds its own type by value and fails to
+// compile with "invalid recursive type".
+func goFieldType(t *common.TypeRef) string {
+	switch t.Kind {
+	case common.NonNullKind:
+		return goFieldType(t.OfType)
+	case common.ListKind:
+		return "[]" + goFieldType(t.OfType)
+	case common.ScalarKind:
+		switch string(t.Name) {
+		case "String", "ID":
+			return "string"
+		case "Int":
+			return "int"
+		case "Float":
+			return "float64"
+		case "Boolean":
+			return "bool"
+		default:
+			return "string"
+		}
+	case common.EnumKind:
+		return strcase.ToCamel(string(t.Name))
+	default:
+		return "*" + strcase.ToCamel(string(t.Name))
+	}
+}
+
+var structTmpl = template.Must(template.New("struct").Parse(`// Code generated by pkg/chaosctl/gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"reflect"
+
+	"github.com/chaos-mesh/chaos-mesh/pkg/chaosctl/common"
+)
+
+// {{.Name}} binds the {{.Name}} GraphQL type to Go, generated from the
+// control plane's introspected schema.
+type {{.Name}} struct {
+{{- range .Fields }}
+	{{ .GoName }} {{ .GoType }} ` + "`" + `json:"{{ .JSONName }}" graphql:"{{ .GraphQLName }}"` + "`" + `
+{{- end }}
+}
+
+func init() {
+	common.RegisterBinding("{{.GraphQLName}}", reflect.TypeOf({{.Name}}{}))
+}
+{{ if .IsRoot }}
+{{ range .Fields }}
+// {{ .GoName }}Path returns the query path for the {{ .GraphQLName }} root
+// field, for use as the leading segment of the path passed to
+// (*common.CtrlClient).Mutate, Subscribe or ListArguments, so callers don't
+// have to spell the GraphQL field name out by hand.
+func {{ .GoName }}Path() []string { return []string{"{{ .GraphQLName }}"} }
+{{ end }}
+{{- end }}
+`))
+
+func (g *Generator) renderStruct(typeDef *common.Type, isRoot bool) string {
+	type fieldData struct {
+		GoName      string
+		GoType      string
+		JSONName    string
+		GraphQLName string
+	}
+
+	fields := typeDef.Fields
+	isInput := typeDef.Kind == common.InputObjectKind
+	if isInput {
+		fields = typeDef.InputFields
+	}
+
+	data := struct {
+		Package     string
+		Name        string
+		GraphQLName string
+		IsRoot      bool
+		Fields      []fieldData
+	}{Package: g.pkgName, Name: strcase.ToCamel(string(typeDef.Name)), GraphQLName: string(typeDef.Name), IsRoot: isRoot}
+
+	for _, field := range fields {
+		data.Fields = append(data.Fields, fieldData{
+			GoName:      strcase.ToCamel(string(field.Name)),
+			GoType:      goFieldType(&field.Type),
+			JSONName:    string(field.Name),
+			GraphQLName: string(field.Name),
+		})
+	}
+
+	var buf bytes.Buffer
+	_ = structTmpl.Execute(&buf, data)
+	return buf.String()
+}
+
+var enumTmpl = template.Must(template.New("enum").Parse(`// Code generated by pkg/chaosctl/gen. DO NOT EDIT.
+
+package {{.Package}}
+
+// {{.Name}} enumerates the values of the {{.Name}} GraphQL enum.
+type {{.Name}} string
+
+const (
+{{- range .Values }}
+	{{ $.Name }}{{ .GoName }} {{ $.Name }} = "{{ .Value }}"
+{{- end }}
+)
+`))
+
+func (g *Generator) renderEnum(typeDef *common.Type) string {
+	type enumValue struct {
+		GoName string
+		Value  string
+	}
+
+	data := struct {
+		Package string
+		Name    string
+		Values  []enumValue
+	}{Package: g.pkgName, Name: strcase.ToCamel(string(typeDef.Name))}
+
+	for _, value := range typeDef.EnumValues {
+		data.Values = append(data.Values, enumValue{GoName: strcase.ToCamel(string(value.Name)), Value: string(value.Name)})
+	}
+	sort.Slice(data.Values, func(i, j int) bool { return data.Values[i].GoName < data.Values[j].GoName })
+
+	var buf bytes.Buffer
+	_ = enumTmpl.Execute(&buf, data)
+	return buf.String()
+}
+
+var unionTmpl = template.Must(template.New("union").Parse(`// Code generated by pkg/chaosctl/gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"reflect"
+
+	"github.com/chaos-mesh/chaos-mesh/pkg/chaosctl/common"
+)
+
+// {{.Name}} is a tagged union over {{.Name}}'s possible types, bound via
+// inline fragments the same way graphql.Fragment composes them at query
+// time.
+type {{.Name}} struct {
+{{- range .Members }}
+	{{ . }} *{{ . }} ` + "`" + `graphql:"... on {{ . }}"` + "`" + `
+{{- end }}
+}
+
+func init() {
+	common.RegisterBinding("{{.GraphQLName}}", reflect.TypeOf({{.Name}}{}))
+}
+`))
+
+func (g *Generator) renderUnion(typeDef *common.Type) string {
+	data := struct {
+		Package     string
+		Name        string
+		GraphQLName string
+		Members     []string
+	}{Package: g.pkgName, Name: strcase.ToCamel(string(typeDef.Name)), GraphQLName: string(typeDef.Name)}
+
+	for _, possible := range typeDef.PossibleTypes {
+		data.Members = append(data.Members, strcase.ToCamel(string(possible.Name)))
+	}
+
+	var buf bytes.Buffer
+	_ = unionTmpl.Execute(&buf, data)
+	return strings.ReplaceAll(buf.String(), "``", "")
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 Chaos Mesh Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gen
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaos-mesh/chaos-mesh/pkg/chaosctl/common"
+)
+
+// mustFormat fails t if src does not round-trip through format.Source,
+// i.e. writeFile would have fallen back to writing unformatted source.
+func mustFormat(t *testing.T, src string) string {
+	t.Helper()
+
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatalf("generated source does not format: %v\n---\n%s", err, src)
+	}
+
+	return string(formatted)
+}
+
+func TestRenderStructPointerWrapsSelfReferentialField(t *testing.T) {
+	node := &common.Type{
+		Kind: common.ObjectKind,
+		Name: "Node",
+		Fields: []*common.Field{
+			{Name: "next", Type: common.TypeRef{Kind: common.ObjectKind, Name: "Node"}},
+		},
+	}
+
+	g := NewGenerator(nil, "", "bindings")
+	src := mustFormat(t, g.renderStruct(node, false))
+
+	// Without the pointer wrap, this struct would embed itself by value and
+	// fail to compile with "invalid recursive type".
+	if !strings.Contains(src, "Next *Node") {
+		t.Fatalf("expected self-referential field to be pointer-wrapped, got:\n%s", src)
+	}
+}
+
+func TestRenderStructEmitsPathHelperPerRootField(t *testing.T) {
+	query := &common.Type{
+		Kind: common.ObjectKind,
+		Name: "Query",
+		Fields: []*common.Field{
+			{Name: "podChaos", Type: common.TypeRef{Kind: common.ObjectKind, Name: "PodChaos"}},
+			{Name: "namespace", Type: common.TypeRef{Kind: common.ScalarKind, Name: "String"}},
+		},
+	}
+
+	g := NewGenerator(nil, "", "bindings")
+	src := mustFormat(t, g.renderStruct(query, true))
+
+	for _, want := range []string{
+		`func PodChaosPath() []string { return []string{"podChaos"} }`,
+		`func NamespacePath() []string { return []string{"namespace"} }`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected root helper %q, got:\n%s", want, src)
+		}
+	}
+}
+
+func TestRenderStructNonRootHasNoPathHelpers(t *testing.T) {
+	podChaos := &common.Type{
+		Kind: common.ObjectKind,
+		Name: "PodChaos",
+		Fields: []*common.Field{
+			{Name: "action", Type: common.TypeRef{Kind: common.ScalarKind, Name: "String"}},
+		},
+	}
+
+	g := NewGenerator(nil, "", "bindings")
+	src := mustFormat(t, g.renderStruct(podChaos, false))
+
+	if strings.Contains(src, "Path() []string") {
+		t.Fatalf("non-root type should not get path helpers, got:\n%s", src)
+	}
+}
+
+func TestWriteFileWritesFormattedSourceWithoutFallback(t *testing.T) {
+	g := NewGenerator(nil, t.TempDir(), "bindings")
+
+	podChaos := &common.Type{
+		Kind: common.ObjectKind,
+		Name: "PodChaos",
+		Fields: []*common.Field{
+			{Name: "action", Type: common.TypeRef{Kind: common.ScalarKind, Name: "String"}},
+		},
+	}
+	body := g.renderStruct(podChaos, false)
+
+	if err := g.writeFile(string(podChaos.Name), body); err != nil {
+		t.Fatalf("writeFile: %v", err)
+	}
+
+	want, err := format.Source([]byte(body))
+	if err != nil {
+		t.Fatalf("format.Source: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(g.outDir, "pod_chaos.gen.go"))
+	if err != nil {
+		t.Fatalf("reading generated file: %v", err)
+	}
+
+	if string(got) != string(want) {
+		t.Fatalf("writeFile fell back to unformatted source:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGoFieldTypeWrapping(t *testing.T) {
+	cases := []struct {
+		name string
+		in   *common.TypeRef
+		want string
+	}{
+		{"scalar string", &common.TypeRef{Kind: common.ScalarKind, Name: "String"}, "string"},
+		{"scalar int", &common.TypeRef{Kind: common.ScalarKind, Name: "Int"}, "int"},
+		{"enum", &common.TypeRef{Kind: common.EnumKind, Name: "PodChaosAction"}, "PodChaosAction"},
+		{"object", &common.TypeRef{Kind: common.ObjectKind, Name: "PodChaos"}, "*PodChaos"},
+		{"interface", &common.TypeRef{Kind: common.InterfaceKind, Name: "Chaos"}, "*Chaos"},
+		{
+			"non-null list of objects",
+			&common.TypeRef{Kind: common.NonNullKind, OfType: &common.TypeRef{
+				Kind: common.ListKind, OfType: &common.TypeRef{Kind: common.ObjectKind, Name: "PodChaos"},
+			}},
+			"[]*PodChaos",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := goFieldType(tc.in); got != tc.want {
+				t.Fatalf("goFieldType() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}